@@ -0,0 +1,64 @@
+package goth
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// FileSystemStore persists sessions server-side as files on disk, keeping
+// only a random session id in the browser cookie. It's a lighter-weight
+// alternative to RedisStore for single-instance deployments that still don't
+// want long-lived tokens living in the browser.
+type FileSystemStore struct {
+	// Dir is the directory session files are written to. It must already exist.
+	Dir string
+
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+}
+
+// Save implements SessionStore.
+func (s *FileSystemStore) Save(w http.ResponseWriter, name, value string) error {
+	id, err := randomSessionID()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(s.path(name, id), []byte(value), 0600); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    id,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		Secure:   s.Secure,
+		HttpOnly: s.HttpOnly,
+	})
+
+	return nil
+}
+
+// Load implements SessionStore.
+func (s *FileSystemStore) Load(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", fmt.Errorf("goth: no session found for %q", name)
+	}
+
+	bits, err := os.ReadFile(s.path(name, cookie.Value))
+	if err != nil {
+		return "", err
+	}
+
+	return string(bits), nil
+}
+
+func (s *FileSystemStore) path(name, id string) string {
+	return filepath.Join(s.Dir, name+"_"+id+".session")
+}
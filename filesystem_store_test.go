@@ -0,0 +1,35 @@
+package goth_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileSystemStore_RoundTrip(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.FileSystemStore{Dir: t.TempDir()}
+
+	res := httptest.NewRecorder()
+	a.NoError(store.Save(res, "sess", "a file-backed session"))
+
+	req := requestWithCookies(res)
+	loaded, err := store.Load(req, "sess")
+	a.NoError(err)
+	a.Equal(loaded, "a file-backed session")
+}
+
+func Test_FileSystemStore_Load_MissingSession(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.FileSystemStore{Dir: t.TempDir()}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	_, err := store.Load(req, "sess")
+	a.Error(err)
+}
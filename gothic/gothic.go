@@ -0,0 +1,207 @@
+// Package gothic wraps the low-level goth.Provider interface with the
+// http.Handler glue most applications actually want: a handler that kicks off
+// the OAuth dance, and one that completes it and hands back a goth.User.
+package gothic
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/netcessities/goth"
+)
+
+// SessionName namespaces the provider session gothic stashes between
+// BeginAuthHandler and CompleteUserAuth.
+const SessionName = "_gothic_session"
+
+// ProviderParamKey is the query parameter gothic reads to determine which
+// registered goth.Provider a request is for.
+const ProviderParamKey = "provider"
+
+// Store is where gothic stashes the marshalled provider session between the
+// redirect to the provider and the callback. It defaults to a CookieStore
+// signed with a key generated at process start, matching goth's historical
+// behavior of keeping everything in the browser, but can be swapped for a
+// goth.RedisStore or goth.FileSystemStore via SetStore so long-lived tokens
+// (refresh tokens, id_tokens) don't have to live in the browser at all.
+// Overriding Store with your own CookieStore is recommended for anything
+// beyond a single-process deployment, since the generated key does not
+// survive a restart and invalidates every in-flight session.
+var Store goth.SessionStore = &goth.CookieStore{HttpOnly: true, SigningKey: generateSigningKey()}
+
+func generateSigningKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("gothic: failed to generate a default CookieStore signing key: " + err.Error())
+	}
+	return key
+}
+
+// SetStore overrides the SessionStore gothic uses to persist provider sessions.
+func SetStore(s goth.SessionStore) {
+	Store = s
+}
+
+// BeginAuthHandler starts the auth process by telling the provider to fetch an
+// authentication end-point, storing the provider's session, and redirecting
+// the user to that end-point.
+func BeginAuthHandler(res http.ResponseWriter, req *http.Request) {
+	url, err := GetAuthURL(res, req)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(res, err)
+		return
+	}
+
+	http.Redirect(res, req, url, http.StatusTemporaryRedirect)
+}
+
+// GetAuthURL starts the provider's auth flow, persists the resulting session
+// via Store, and returns the URL the user should be redirected to.
+//
+// If the request doesn't supply a state, GetAuthURL generates a random one
+// and stashes it via Store so CompleteUserAuth can reject a callback whose
+// state doesn't match, guarding against CSRF and session fixation.
+func GetAuthURL(res http.ResponseWriter, req *http.Request) (string, error) {
+	providerName, err := GetProviderName(req)
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := goth.GetProvider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	state := req.URL.Query().Get("state")
+	if state == "" {
+		state, err = randomState()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sess, err := provider.BeginAuth(state)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := sess.GetAuthURL()
+	if err != nil {
+		return "", err
+	}
+
+	if err := StoreInSession(providerName, sess.Marshal(), res); err != nil {
+		return "", err
+	}
+
+	if err := Store.Save(res, stateKey(providerName), state); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// CompleteUserAuth looks up the session stashed by BeginAuthHandler,
+// authorizes it against the provider's callback parameters, and returns the
+// resulting goth.User.
+func CompleteUserAuth(res http.ResponseWriter, req *http.Request) (goth.User, error) {
+	providerName, err := GetProviderName(req)
+	if err != nil {
+		return goth.User{}, err
+	}
+
+	provider, err := goth.GetProvider(providerName)
+	if err != nil {
+		return goth.User{}, err
+	}
+
+	value, err := GetFromSession(providerName, req)
+	if err != nil {
+		return goth.User{}, err
+	}
+
+	sess, err := provider.UnmarshalSession(value)
+	if err != nil {
+		return goth.User{}, err
+	}
+
+	if user, err := provider.FetchUser(sess); err == nil {
+		// the session was already authorized, e.g. when an access token was
+		// restored from a server-side SessionStore and still has time left on it
+		return user, nil
+	}
+
+	if err := validateState(providerName, req); err != nil {
+		return goth.User{}, err
+	}
+
+	if _, err := sess.Authorize(provider, req.URL.Query()); err != nil {
+		return goth.User{}, err
+	}
+
+	if err := StoreInSession(providerName, sess.Marshal(), res); err != nil {
+		return goth.User{}, err
+	}
+
+	return provider.FetchUser(sess)
+}
+
+// GetProviderName returns the name of the goth.Provider the given request is for.
+func GetProviderName(req *http.Request) (string, error) {
+	if p := req.URL.Query().Get(ProviderParamKey); p != "" {
+		return p, nil
+	}
+
+	if p := req.FormValue(ProviderParamKey); p != "" {
+		return p, nil
+	}
+
+	return "", errors.New("gothic: no provider specified in the request")
+}
+
+// StoreInSession stashes value (the output of Session.Marshal) under the
+// given provider's session key via Store.
+func StoreInSession(providerName, value string, res http.ResponseWriter) error {
+	return Store.Save(res, sessionKey(providerName), value)
+}
+
+// GetFromSession retrieves the value previously stashed by StoreInSession for
+// the given provider via Store.
+func GetFromSession(providerName string, req *http.Request) (string, error) {
+	return Store.Load(req, sessionKey(providerName))
+}
+
+func sessionKey(providerName string) string {
+	return SessionName + "_" + providerName
+}
+
+func stateKey(providerName string) string {
+	return SessionName + "_state_" + providerName
+}
+
+// validateState compares the request's state callback parameter against the
+// one stashed by GetAuthURL, failing closed if none was stashed at all.
+func validateState(providerName string, req *http.Request) error {
+	expected, err := Store.Load(req, stateKey(providerName))
+	if err != nil {
+		return fmt.Errorf("gothic: no state was stashed for %q, cannot validate the callback", providerName)
+	}
+
+	if got := req.URL.Query().Get("state"); got != expected {
+		return errors.New("gothic: state parameter does not match the one generated at BeginAuth")
+	}
+
+	return nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,60 @@
+package gothic_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/netcessities/goth/gothic"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StoreInSession_GetFromSession_RoundTrip(t *testing.T) {
+	a := assert.New(t)
+
+	res := httptest.NewRecorder()
+	a.NoError(gothic.StoreInSession("example", `{"AccessToken":"abc"}`, res))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	value, err := gothic.GetFromSession("example", req)
+	a.NoError(err)
+	a.Equal(value, `{"AccessToken":"abc"}`)
+}
+
+func Test_SetStore(t *testing.T) {
+	a := assert.New(t)
+
+	original := gothic.Store
+	defer gothic.SetStore(original)
+
+	gothic.SetStore(&goth.FileSystemStore{Dir: t.TempDir()})
+
+	res := httptest.NewRecorder()
+	a.NoError(gothic.StoreInSession("example", `{"AccessToken":"xyz"}`, res))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	value, err := gothic.GetFromSession("example", req)
+	a.NoError(err)
+	a.Equal(value, `{"AccessToken":"xyz"}`)
+}
+
+func Test_GetProviderName(t *testing.T) {
+	a := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/auth?provider=keycloak", nil)
+	name, err := gothic.GetProviderName(req)
+	a.NoError(err)
+	a.Equal(name, "keycloak")
+
+	req = httptest.NewRequest("GET", "/auth", nil)
+	_, err = gothic.GetProviderName(req)
+	a.Error(err)
+}
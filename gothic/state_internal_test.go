@@ -0,0 +1,53 @@
+package gothic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateState_RejectsMismatch(t *testing.T) {
+	a := assert.New(t)
+
+	original := Store
+	defer SetStore(original)
+	SetStore(&goth.CookieStore{SigningKey: []byte("test-signing-key")})
+
+	res := httptest.NewRecorder()
+	a.NoError(Store.Save(res, stateKey("example"), "expected-state"))
+
+	req := httptest.NewRequest(http.MethodGet, "/?state=wrong-state", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	a.Error(validateState("example", req))
+}
+
+func Test_ValidateState_AcceptsMatch(t *testing.T) {
+	a := assert.New(t)
+
+	original := Store
+	defer SetStore(original)
+	SetStore(&goth.CookieStore{SigningKey: []byte("test-signing-key")})
+
+	res := httptest.NewRecorder()
+	a.NoError(Store.Save(res, stateKey("example"), "expected-state"))
+
+	req := httptest.NewRequest(http.MethodGet, "/?state=expected-state", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	a.NoError(validateState("example", req))
+}
+
+func Test_ValidateState_RejectsWhenNoneStashed(t *testing.T) {
+	a := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/?state=anything", nil)
+	a.Error(validateState("example", req))
+}
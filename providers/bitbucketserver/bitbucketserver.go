@@ -0,0 +1,236 @@
+// Package bitbucketserver implements the OAuth1 protocol for authenticating users
+// through an on-prem Bitbucket Server (formerly Stash) instance. For Bitbucket
+// Cloud's OAuth2 flow, see `providers/bitbucket` instead.
+package bitbucketserver
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/dghubble/oauth1"
+	"github.com/netcessities/goth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	requestTokenPath = "/plugins/servlet/oauth/request-token"
+	authorizePath    = "/plugins/servlet/oauth/authorize"
+	accessTokenPath  = "/plugins/servlet/oauth/access-token"
+
+	whoamiPath = "/plugins/servlet/applinks/whoami"
+	userPath   = "/rest/api/1.0/users/"
+)
+
+// Provider is the implementation of `goth.Provider` for accessing a Bitbucket
+// Server (Stash) instance via three-legged OAuth1.
+type Provider struct {
+	ConsumerKey  string
+	CallbackURL  string
+	BaseURL      string
+	HTTPClient   *http.Client
+	config       oauth1.Config
+	providerName string
+}
+
+// New creates a new Bitbucket Server provider, and sets up important connection
+// details. privateKeyPEM may be either a PEM-encoded RSA private key, or a path
+// to a file containing one. You should always call `bitbucketserver.New` to get
+// a new Provider. Never try to create one manually.
+func New(consumerKey, privateKeyPEM, callbackURL, baseURL string, scopes ...string) (*Provider, error) {
+	key, err := loadRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("bitbucketserver: %w", err)
+	}
+
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	p := &Provider{
+		ConsumerKey:  consumerKey,
+		CallbackURL:  callbackURL,
+		BaseURL:      baseURL,
+		providerName: "bitbucketserver",
+	}
+
+	p.config = oauth1.Config{
+		ConsumerKey: consumerKey,
+		CallbackURL: callbackURL,
+		Endpoint: oauth1.Endpoint{
+			RequestTokenURL: baseURL + requestTokenPath,
+			AuthorizeURL:    baseURL + authorizePath,
+			AccessTokenURL:  baseURL + accessTokenPath,
+		},
+		Signer: &oauth1.RSASigner{PrivateKey: key},
+	}
+
+	return p, nil
+}
+
+func loadRSAPrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	raw := []byte(privateKeyPEM)
+	if !strings.Contains(privateKeyPEM, "-----BEGIN") {
+		bits, err := ioutil.ReadFile(privateKeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("could not read private key file: %w", err)
+		}
+		raw = bits
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse RSA private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// Name is the name used to retrieve this provider later.
+func (p *Provider) Name() string {
+	return p.providerName
+}
+
+// SetName is to update the name of the provider (needed in case of multiple providers of 1 type)
+func (p *Provider) SetName(name string) {
+	p.providerName = name
+}
+
+func (p *Provider) Client() *http.Client {
+	return goth.HTTPClientWithFallBack(p.HTTPClient)
+}
+
+// Debug is a no-op for the bitbucketserver package.
+func (p *Provider) Debug(debug bool) {}
+
+// BeginAuth asks Bitbucket Server for a request token, and returns the
+// authorization end-point built from it.
+func (p *Provider) BeginAuth(state string) (goth.Session, error) {
+	requestToken, requestSecret, err := p.config.RequestToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := p.config.AuthorizationURL(requestToken)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		AuthURL:       authorizationURL.String(),
+		RequestToken:  requestToken,
+		RequestSecret: requestSecret,
+	}
+	return session, nil
+}
+
+// FetchUser will go to Bitbucket Server and access basic information about the user.
+func (p *Provider) FetchUser(session goth.Session) (goth.User, error) {
+	sess := session.(*Session)
+	user := goth.User{
+		AccessToken: sess.AccessToken,
+		Provider:    p.Name(),
+	}
+
+	if sess.AccessToken == "" {
+		return user, fmt.Errorf("%s cannot get user information without accessToken", p.providerName)
+	}
+
+	client := p.config.Client(goth.ContextForClient(p.Client()), oauth1.NewToken(sess.AccessToken, sess.AccessSecret))
+
+	slug, err := p.fetchSlug(client)
+	if err != nil {
+		return user, err
+	}
+
+	response, err := client.Get(p.BaseURL + userPath + slug)
+	if err != nil {
+		return user, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return user, fmt.Errorf("%s responded with a %d trying to fetch user information", p.providerName, response.StatusCode)
+	}
+
+	err = userFromReader(response.Body, &user)
+	return user, err
+}
+
+// fetchSlug asks Bitbucket Server's applinks plugin which user the access
+// token belongs to, since the REST API addresses users by slug rather than
+// by the access token itself.
+func (p *Provider) fetchSlug(client *http.Client) (string, error) {
+	response, err := client.Get(p.BaseURL + whoamiPath)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s responded with a %d trying to resolve the authenticated user", p.providerName, response.StatusCode)
+	}
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(bits)), nil
+}
+
+func userFromReader(reader io.Reader, user *goth.User) error {
+	u := struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+		Slug         string `json:"slug"`
+		Links        struct {
+			Self []struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+	}{}
+
+	if err := json.NewDecoder(reader).Decode(&u); err != nil {
+		return err
+	}
+
+	user.UserID = fmt.Sprintf("%d", u.ID)
+	user.NickName = u.Name
+	user.Name = u.DisplayName
+	user.Email = u.EmailAddress
+	if len(u.Links.Self) > 0 {
+		user.AvatarURL = u.Links.Self[0].Href
+	}
+
+	return nil
+}
+
+// RefreshToken is not provided by Bitbucket Server's OAuth1 flow.
+func (p *Provider) RefreshToken(refreshToken string) (*oauth2.Token, error) {
+	return nil, fmt.Errorf("refresh token is not provided by %s", p.providerName)
+}
+
+// RefreshTokenAvailable refresh token is not provided by bitbucketserver.
+func (p *Provider) RefreshTokenAvailable() bool {
+	return false
+}
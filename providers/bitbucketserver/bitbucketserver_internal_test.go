@@ -0,0 +1,25 @@
+package bitbucketserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_userFromReader(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	body := `{"id":42,"name":"jdoe","displayName":"Jane Doe","emailAddress":"jane@example.com","slug":"jdoe","links":{"self":[{"href":"https://stash.example.com/users/jdoe"}]}}`
+
+	user := goth.User{}
+	err := userFromReader(strings.NewReader(body), &user)
+	a.NoError(err)
+	a.Equal(user.UserID, "42")
+	a.Equal(user.NickName, "jdoe")
+	a.Equal(user.Name, "Jane Doe")
+	a.Equal(user.Email, "jane@example.com")
+	a.Equal(user.AvatarURL, "https://stash.example.com/users/jdoe")
+}
@@ -0,0 +1,66 @@
+package bitbucketserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/netcessities/goth"
+)
+
+// Session stores data during the auth process with Bitbucket Server. Unlike the
+// OAuth2 providers, the request-token secret issued in `BeginAuth` has to be
+// carried through to `Authorize`, since RSA-SHA1 signing needs it alongside the
+// `oauth_verifier` the user brings back from the authorization redirect.
+type Session struct {
+	AuthURL       string
+	RequestToken  string
+	RequestSecret string
+	AccessToken   string
+	AccessSecret  string
+}
+
+// GetAuthURL will return the URL set by calling the `BeginAuth` function on the
+// Bitbucket Server provider.
+func (s Session) GetAuthURL() (string, error) {
+	if s.AuthURL == "" {
+		return "", errors.New(goth.NoAuthUrlErrorMessage)
+	}
+	return s.AuthURL, nil
+}
+
+// Authorize exchanges the request token and the `oauth_verifier` supplied by
+// Bitbucket Server for a long-lived access token.
+func (s *Session) Authorize(provider goth.Provider, params goth.Params) (string, error) {
+	p := provider.(*Provider)
+
+	if s.RequestToken == "" || s.RequestSecret == "" {
+		return "", fmt.Errorf("%s: session is missing the request token issued by BeginAuth", p.providerName)
+	}
+
+	accessToken, accessSecret, err := p.config.AccessToken(s.RequestToken, s.RequestSecret, params.Get("oauth_verifier"))
+	if err != nil {
+		return "", err
+	}
+
+	s.AccessToken = accessToken
+	s.AccessSecret = accessSecret
+	return accessToken, nil
+}
+
+// Marshal the session into a string
+func (s Session) Marshal() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func (s Session) String() string {
+	return s.Marshal()
+}
+
+// UnmarshalSession will unmarshal a JSON string into a session.
+func (p *Provider) UnmarshalSession(data string) (goth.Session, error) {
+	s := &Session{}
+	err := json.Unmarshal([]byte(data), s)
+	return s, err
+}
@@ -0,0 +1,95 @@
+package bitbucketserver_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/netcessities/goth/providers/bitbucketserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New_WithPEMString(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, err := bitbucketserver.New("consumer-key", testPrivateKeyPEM(t), "/foo", "https://stash.example.com")
+	a.NoError(err)
+	a.Equal(provider.ConsumerKey, "consumer-key")
+	a.Equal(provider.BaseURL, "https://stash.example.com")
+}
+
+func Test_New_WithPEMFile(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "consumer.pem")
+	a.NoError(os.WriteFile(path, []byte(testPrivateKeyPEM(t)), 0600))
+
+	provider, err := bitbucketserver.New("consumer-key", path, "/foo", "https://stash.example.com/")
+	a.NoError(err)
+	a.Equal(provider.BaseURL, "https://stash.example.com")
+}
+
+func Test_New_RejectsInvalidKey(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	_, err := bitbucketserver.New("consumer-key", "not a pem key", "/foo", "https://stash.example.com")
+	a.Error(err)
+}
+
+func Test_Implements_Provider(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, err := bitbucketserver.New("consumer-key", testPrivateKeyPEM(t), "/foo", "https://stash.example.com")
+	a.NoError(err)
+	a.Implements((*goth.Provider)(nil), provider)
+}
+
+func Test_SessionFromJSON(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, err := bitbucketserver.New("consumer-key", testPrivateKeyPEM(t), "/foo", "https://stash.example.com")
+	a.NoError(err)
+
+	s, err := provider.UnmarshalSession(`{"AuthURL":"https://stash.example.com/authorize","RequestToken":"rt","RequestSecret":"rs","AccessToken":"at","AccessSecret":"as"}`)
+	a.NoError(err)
+	session := s.(*bitbucketserver.Session)
+	a.Equal(session.AuthURL, "https://stash.example.com/authorize")
+	a.Equal(session.RequestToken, "rt")
+	a.Equal(session.RequestSecret, "rs")
+	a.Equal(session.AccessToken, "at")
+	a.Equal(session.AccessSecret, "as")
+}
+
+func Test_Session_GetAuthURL_RequiresBeginAuth(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	session := &bitbucketserver.Session{}
+	_, err := session.GetAuthURL()
+	a.Error(err)
+}
+
+func testPrivateKeyPEM(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating test key: %v", err)
+	}
+
+	block := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}
+	return string(pem.EncodeToMemory(block))
+}
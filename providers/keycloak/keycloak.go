@@ -0,0 +1,119 @@
+// Package keycloak implements the OAuth2/OpenID Connect protocol for authenticating
+// users through a Keycloak realm, on top of `providers/openidconnect`.
+package keycloak
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/netcessities/goth"
+	"github.com/netcessities/goth/providers/openidconnect"
+)
+
+// Provider is the implementation of `goth.Provider` for accessing Keycloak.
+type Provider struct {
+	*openidconnect.Provider
+	endSessionURL string
+}
+
+// New creates a new Keycloak provider, deriving the authorization, token,
+// userinfo, and end-session endpoints from baseURL and realm. You should
+// always call `keycloak.New` to get a new Provider. Never try to create one
+// manually.
+func New(clientID, secret, callbackURL, baseURL, realm string, scopes ...string) *Provider {
+	issuer := strings.TrimRight(baseURL, "/") + "/realms/" + realm
+	endpoint := issuer + "/protocol/openid-connect"
+
+	config := &openidconnect.OpenIDConfig{
+		Issuer:                issuer,
+		AuthorizationEndpoint: endpoint + "/auth",
+		TokenEndpoint:         endpoint + "/token",
+		UserinfoEndpoint:      endpoint + "/userinfo",
+		JWKSURI:               endpoint + "/certs",
+	}
+
+	oidcProvider := openidconnect.NewWithConfig(clientID, secret, callbackURL, config, scopes...)
+	oidcProvider.SetName("keycloak")
+
+	return &Provider{
+		Provider:      oidcProvider,
+		endSessionURL: endpoint + "/logout",
+	}
+}
+
+// FetchUser fetches the standard OpenID Connect claims, then decodes the
+// realm and client roles embedded in the access token and surfaces them on
+// `goth.User.RawData` as `Roles` and `ClientRoles`, since role-based
+// authorization is the main reason applications reach for Keycloak.
+func (p *Provider) FetchUser(session goth.Session) (goth.User, error) {
+	user, err := p.Provider.FetchUser(session)
+	if err != nil {
+		return user, err
+	}
+
+	sess := session.(*openidconnect.Session)
+	roles, clientRoles, err := decodeRoles(sess.AccessToken)
+	if err != nil {
+		return user, err
+	}
+
+	if user.RawData == nil {
+		user.RawData = map[string]interface{}{}
+	}
+	user.RawData["Roles"] = roles
+	user.RawData["ClientRoles"] = clientRoles
+
+	return user, nil
+}
+
+// Logout returns the RP-initiated logout URL that ends the user's Keycloak
+// session and redirects back to postLogoutRedirectURI.
+func (p *Provider) Logout(idToken, postLogoutRedirectURI string) (string, error) {
+	if idToken == "" {
+		return "", fmt.Errorf("keycloak: cannot logout without an id_token")
+	}
+
+	values := url.Values{
+		"id_token_hint":            {idToken},
+		"post_logout_redirect_uri": {postLogoutRedirectURI},
+	}
+	return p.endSessionURL + "?" + values.Encode(), nil
+}
+
+// decodeRoles pulls `realm_access.roles` and `resource_access.<client>.roles`
+// out of the access token's claims. The access token is not re-verified here:
+// it was already obtained directly from the token endpoint over TLS, so only
+// the JSON payload needs decoding, not the signature.
+func decodeRoles(accessToken string) ([]string, map[string][]string, error) {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("keycloak: access_token is not a well-formed JWT")
+	}
+
+	claimsBits, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims := struct {
+		RealmAccess struct {
+			Roles []string `json:"roles"`
+		} `json:"realm_access"`
+		ResourceAccess map[string]struct {
+			Roles []string `json:"roles"`
+		} `json:"resource_access"`
+	}{}
+	if err := json.Unmarshal(claimsBits, &claims); err != nil {
+		return nil, nil, err
+	}
+
+	clientRoles := make(map[string][]string, len(claims.ResourceAccess))
+	for client, access := range claims.ResourceAccess {
+		clientRoles[client] = access.Roles
+	}
+
+	return claims.RealmAccess.Roles, clientRoles, nil
+}
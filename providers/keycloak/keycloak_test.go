@@ -0,0 +1,50 @@
+package keycloak_test
+
+import (
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/netcessities/goth/providers/keycloak"
+	"github.com/netcessities/goth/providers/openidconnect"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider := keycloakProvider()
+	a.Implements((*goth.Provider)(nil), provider)
+	a.Equal(provider.Name(), "keycloak")
+}
+
+func Test_BeginAuth(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider := keycloakProvider()
+	session, err := provider.BeginAuth("test_state")
+	a.NoError(err)
+
+	s := session.(*openidconnect.Session)
+	a.Contains(s.AuthURL, "https://keycloak.example.com/realms/myrealm/protocol/openid-connect/auth")
+	a.Contains(s.AuthURL, "state=test_state")
+}
+
+func Test_Logout(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider := keycloakProvider()
+	logoutURL, err := provider.Logout("id-token-value", "https://app.example.com/")
+	a.NoError(err)
+	a.Contains(logoutURL, "https://keycloak.example.com/realms/myrealm/protocol/openid-connect/logout")
+	a.Contains(logoutURL, "id_token_hint=id-token-value")
+
+	_, err = provider.Logout("", "https://app.example.com/")
+	a.Error(err)
+}
+
+func keycloakProvider() *keycloak.Provider {
+	return keycloak.New("my-client", "secret", "/foo", "https://keycloak.example.com", "myrealm")
+}
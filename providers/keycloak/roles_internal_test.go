@@ -0,0 +1,46 @@
+package keycloak
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_decodeRoles(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	accessToken := unverifiedJWT(map[string]interface{}{
+		"realm_access": map[string]interface{}{
+			"roles": []string{"offline_access", "uma_authorization"},
+		},
+		"resource_access": map[string]interface{}{
+			"my-client": map[string]interface{}{
+				"roles": []string{"admin"},
+			},
+		},
+	})
+
+	roles, clientRoles, err := decodeRoles(accessToken)
+	a.NoError(err)
+	a.Equal(roles, []string{"offline_access", "uma_authorization"})
+	a.Equal(clientRoles, map[string][]string{"my-client": {"admin"}})
+}
+
+func Test_decodeRoles_RejectsMalformedToken(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	_, _, err := decodeRoles("not-a-jwt")
+	a.Error(err)
+}
+
+// unverifiedJWT builds a JWT with the given claims and an empty signature,
+// since decodeRoles only inspects the claims segment.
+func unverifiedJWT(claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	body, _ := json.Marshal(claims)
+	return header + "." + base64.RawURLEncoding.EncodeToString(body) + "."
+}
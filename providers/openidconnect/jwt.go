@@ -0,0 +1,274 @@
+package openidconnect
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwk is a single JSON Web Key as published at a provider's `jwks_uri`.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwks is a cached `{"keys": [...]}` document fetched from a provider's `jwks_uri`.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+func fetchJWKS(client *http.Client, jwksURI string) (*jwks, error) {
+	response, err := client.Get(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks_uri responded with a %d", response.StatusCode)
+	}
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &jwks{}
+	if err := json.Unmarshal(bits, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// getJWKS returns the cached JWKS document, fetching and populating it on the
+// first call. Provider instances are registered once and FetchUser may be
+// called concurrently for many simultaneous logins, so access to jwksCache is
+// guarded by jwksMu rather than mutated unsynchronized from FetchUser.
+func (p *Provider) getJWKS() (*jwks, error) {
+	p.jwksMu.RLock()
+	set := p.jwksCache
+	p.jwksMu.RUnlock()
+
+	if set != nil {
+		return set, nil
+	}
+
+	return p.refreshJWKS()
+}
+
+// refreshJWKS re-fetches the JWKS document from jwks_uri and replaces the cache.
+func (p *Provider) refreshJWKS() (*jwks, error) {
+	set, err := fetchJWKS(p.Client(), p.openIDConfig.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.jwksMu.Lock()
+	p.jwksCache = set
+	p.jwksMu.Unlock()
+
+	return set, nil
+}
+
+func (s *jwks) key(kid string) (*jwk, bool) {
+	for i := range s.Keys {
+		if s.Keys[i].Kid == kid {
+			return &s.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(seg)
+}
+
+// verifyIDToken checks the id_token's RS256/ES256 signature against the provider's
+// JWKS, validates `iss`, `aud`, `exp`, and `nonce`, and returns the decoded claims.
+func (p *Provider) verifyIDToken(idToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%s: id_token is not a well-formed JWT", p.providerName)
+	}
+
+	headerBits, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := json.Unmarshal(headerBits, &header); err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, fmt.Errorf("%s: unsupported id_token signing algorithm %q", p.providerName, header.Alg)
+	}
+
+	set, err := p.getJWKS()
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := set.key(header.Kid)
+	if !ok {
+		// the signing key may have rotated since we last cached the JWKS document
+		set, err = p.refreshJWKS()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok = set.key(header.Kid)
+		if !ok {
+			return nil, fmt.Errorf("%s: no matching key for kid %q in jwks_uri", p.providerName, header.Kid)
+		}
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, signingInput, signature); err != nil {
+		return nil, err
+	}
+
+	claimsBits, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(claimsBits, &claims); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != p.openIDConfig.Issuer {
+		return nil, fmt.Errorf("%s: id_token iss %q does not match expected issuer %q", p.providerName, iss, p.openIDConfig.Issuer)
+	}
+
+	if !audienceContains(claims["aud"], p.ClientKey) {
+		return nil, fmt.Errorf("%s: id_token aud does not contain client_id %q", p.providerName, p.ClientKey)
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: id_token is missing a required exp claim", p.providerName)
+	}
+	if time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, fmt.Errorf("%s: id_token has expired", p.providerName)
+	}
+
+	if nonce != "" {
+		if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+			return nil, fmt.Errorf("%s: id_token nonce does not match the one issued at BeginAuth", p.providerName)
+		}
+	}
+
+	return claims, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func verifySignature(alg string, key *jwk, signingInput string, signature []byte) error {
+	switch alg {
+	case "RS256":
+		pub, err := rsaPublicKey(key)
+		if err != nil {
+			return err
+		}
+		hashed := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], signature)
+	case "ES256":
+		pub, err := ecdsaPublicKey(key)
+		if err != nil {
+			return err
+		}
+		hashed := sha256.Sum256([]byte(signingInput))
+
+		if len(signature) != 64 {
+			return fmt.Errorf("openidconnect: malformed ES256 signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, hashed[:], r, s) {
+			return fmt.Errorf("openidconnect: id_token signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("openidconnect: unsupported signing algorithm %q", alg)
+	}
+}
+
+func rsaPublicKey(key *jwk) (*rsa.PublicKey, error) {
+	nBytes, err := decodeSegment(key.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := decodeSegment(key.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+func ecdsaPublicKey(key *jwk) (*ecdsa.PublicKey, error) {
+	xBytes, err := decodeSegment(key.X)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := decodeSegment(key.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Crv != "P-256" {
+		return nil, fmt.Errorf("openidconnect: unsupported EC curve %q", key.Crv)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}, nil
+}
@@ -0,0 +1,279 @@
+// Package openidconnect implements the OpenID Connect protocol for authenticating users through
+// any standards-compliant identity provider (Keycloak, Auth0, Okta, Dex, ...).
+package openidconnect
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/netcessities/goth"
+	"golang.org/x/oauth2"
+)
+
+// ErrOpenIDConnectInitialize is returned by `New` when the auto-discovery document
+// could not be fetched or parsed, so misconfigured identity providers surface
+// clearly instead of panicking the first time the provider is used.
+type ErrOpenIDConnectInitialize struct {
+	Err error
+}
+
+func (e *ErrOpenIDConnectInitialize) Error() string {
+	return fmt.Sprintf("openidconnect: failed to initialize provider: %v", e.Err)
+}
+
+func (e *ErrOpenIDConnectInitialize) Unwrap() error {
+	return e.Err
+}
+
+// OpenIDConfig holds the subset of the `.well-known/openid-configuration` document
+// that the provider needs to drive the auth code flow and validate tokens.
+type OpenIDConfig struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	Issuer                string `json:"issuer"`
+}
+
+// Provider is the implementation of `goth.Provider` for accessing a generic
+// OpenID Connect identity provider.
+type Provider struct {
+	ClientKey    string
+	Secret       string
+	CallbackURL  string
+	HTTPClient   *http.Client
+	config       *oauth2.Config
+	providerName string
+	openIDConfig *OpenIDConfig
+
+	jwksMu    sync.RWMutex
+	jwksCache *jwks
+}
+
+// New creates a new OpenID Connect provider by fetching and caching the
+// provider's auto-discovery document. You should always call
+// `openidconnect.New` to get a new Provider. Never try to create one manually.
+func New(clientKey, secret, callbackURL, autoDiscoveryURL string, scopes ...string) (*Provider, error) {
+	p := &Provider{
+		ClientKey:    clientKey,
+		Secret:       secret,
+		CallbackURL:  callbackURL,
+		providerName: "openid-connect",
+	}
+
+	openIDConfig, err := fetchOpenIDConfig(p.Client(), autoDiscoveryURL)
+	if err != nil {
+		return nil, &ErrOpenIDConnectInitialize{Err: err}
+	}
+
+	p.openIDConfig = openIDConfig
+	p.config = newConfig(p, scopes, openIDConfig)
+	return p, nil
+}
+
+// NewWithConfig creates a new OpenID Connect provider from an already-known
+// OpenIDConfig, skipping the auto-discovery request. This is the extension
+// point vendor-specific providers (e.g. `providers/keycloak`) build on when
+// they can derive the endpoints themselves instead of discovering them.
+func NewWithConfig(clientKey, secret, callbackURL string, openIDConfig *OpenIDConfig, scopes ...string) *Provider {
+	p := &Provider{
+		ClientKey:    clientKey,
+		Secret:       secret,
+		CallbackURL:  callbackURL,
+		providerName: "openid-connect",
+		openIDConfig: openIDConfig,
+	}
+	p.config = newConfig(p, scopes, openIDConfig)
+	return p
+}
+
+func fetchOpenIDConfig(client *http.Client, autoDiscoveryURL string) (*OpenIDConfig, error) {
+	response, err := client.Get(autoDiscoveryURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auto-discovery document responded with a %d", response.StatusCode)
+	}
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &OpenIDConfig{}
+	if err := json.NewDecoder(bytes.NewReader(bits)).Decode(config); err != nil {
+		return nil, err
+	}
+
+	if config.AuthorizationEndpoint == "" || config.TokenEndpoint == "" {
+		return nil, fmt.Errorf("auto-discovery document is missing authorization_endpoint or token_endpoint")
+	}
+
+	return config, nil
+}
+
+// Name is the name used to retrieve this provider later.
+func (p *Provider) Name() string {
+	return p.providerName
+}
+
+// SetName is to update the name of the provider (needed in case of multiple providers of 1 type)
+func (p *Provider) SetName(name string) {
+	p.providerName = name
+}
+
+func (p *Provider) Client() *http.Client {
+	return goth.HTTPClientWithFallBack(p.HTTPClient)
+}
+
+// Debug is a no-op for the openidconnect package.
+func (p *Provider) Debug(debug bool) {}
+
+// BeginAuth asks the identity provider for an authentication end-point and
+// stashes a nonce on the session so it can be validated against the returned
+// id_token in `FetchUser`.
+func (p *Provider) BeginAuth(state string) (goth.Session, error) {
+	nonce, err := randomNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	aurl := p.config.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+	session := &Session{
+		AuthURL: aurl,
+		Nonce:   nonce,
+	}
+	return session, nil
+}
+
+// FetchUser validates the id_token returned during the code exchange and merges
+// its claims with the `/userinfo` response into a `goth.User`.
+func (p *Provider) FetchUser(session goth.Session) (goth.User, error) {
+	sess := session.(*Session)
+	user := goth.User{
+		AccessToken:  sess.AccessToken,
+		Provider:     p.Name(),
+		ExpiresAt:    sess.ExpiresAt,
+		RefreshToken: sess.RefreshToken,
+	}
+
+	if user.AccessToken == "" {
+		return user, fmt.Errorf("%s cannot get user information without accessToken", p.providerName)
+	}
+
+	if sess.IDToken == "" {
+		return user, fmt.Errorf("%s cannot get user information without an id_token", p.providerName)
+	}
+
+	claims, err := p.verifyIDToken(sess.IDToken, sess.Nonce)
+	if err != nil {
+		return user, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.openIDConfig.UserinfoEndpoint, nil)
+	if err != nil {
+		return user, err
+	}
+	req.Header.Set("Authorization", "Bearer "+sess.AccessToken)
+
+	response, err := p.Client().Do(req)
+	if err != nil {
+		return user, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return user, fmt.Errorf("%s responded with a %d trying to fetch user information", p.providerName, response.StatusCode)
+	}
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return user, err
+	}
+
+	userinfo := map[string]interface{}{}
+	if err := json.NewDecoder(bytes.NewReader(bits)).Decode(&userinfo); err != nil {
+		return user, err
+	}
+
+	for k, v := range userinfo {
+		claims[k] = v
+	}
+	user.RawData = claims
+
+	userFromClaims(claims, &user)
+	return user, nil
+}
+
+func randomNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func userFromClaims(claims map[string]interface{}, user *goth.User) {
+	if sub, ok := claims["sub"].(string); ok {
+		user.UserID = sub
+	}
+	if email, ok := claims["email"].(string); ok {
+		user.Email = email
+	}
+	if name, ok := claims["name"].(string); ok {
+		user.Name = name
+	}
+	if picture, ok := claims["picture"].(string); ok {
+		user.AvatarURL = picture
+	}
+	if nickname, ok := claims["preferred_username"].(string); ok {
+		user.NickName = nickname
+	}
+}
+
+func newConfig(provider *Provider, scopes []string, openIDConfig *OpenIDConfig) *oauth2.Config {
+	c := &oauth2.Config{
+		ClientID:     provider.ClientKey,
+		ClientSecret: provider.Secret,
+		RedirectURL:  provider.CallbackURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  openIDConfig.AuthorizationEndpoint,
+			TokenURL: openIDConfig.TokenEndpoint,
+		},
+		Scopes: []string{"openid"},
+	}
+
+	defaultScopes := map[string]struct{}{
+		"openid": {},
+	}
+
+	for _, scope := range scopes {
+		if _, exists := defaultScopes[scope]; !exists {
+			c.Scopes = append(c.Scopes, scope)
+		}
+	}
+
+	return c
+}
+
+// RefreshToken refreshes the access token using the standard
+// `grant_type=refresh_token` flow against the provider's token endpoint.
+func (p *Provider) RefreshToken(refreshToken string) (*oauth2.Token, error) {
+	token := &oauth2.Token{RefreshToken: refreshToken}
+	ts := p.config.TokenSource(goth.ContextForClient(p.Client()), token)
+	return ts.Token()
+}
+
+// RefreshTokenAvailable refresh token is provided by openidconnect.
+func (p *Provider) RefreshTokenAvailable() bool {
+	return true
+}
@@ -0,0 +1,231 @@
+package openidconnect_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/netcessities/goth/providers/openidconnect"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New_FailsWithBadDiscoveryDocument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	_, err := openidconnect.New("key", "secret", "/foo", ts.URL+"/.well-known/openid-configuration")
+	a.Error(err)
+	a.IsType(&openidconnect.ErrOpenIDConnectInitialize{}, err)
+}
+
+func Test_Implements_Provider(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, ts := newTestProvider(t)
+	defer ts.Close()
+
+	a.Implements((*goth.Provider)(nil), provider)
+}
+
+func Test_BeginAuth(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, ts := newTestProvider(t)
+	defer ts.Close()
+
+	session, err := provider.BeginAuth("test_state")
+	a.NoError(err)
+	s := session.(*openidconnect.Session)
+	a.Contains(s.AuthURL, "state=test_state")
+	a.Contains(s.AuthURL, "nonce=")
+	a.NotEmpty(s.Nonce)
+}
+
+func Test_SessionFromJSON(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider, ts := newTestProvider(t)
+	defer ts.Close()
+
+	s, err := provider.UnmarshalSession(`{"AuthURL":"http://idp.example.com/auth_url","AccessToken":"1234567890"}`)
+	a.NoError(err)
+	session := s.(*openidconnect.Session)
+	a.Equal(session.AuthURL, "http://idp.example.com/auth_url")
+	a.Equal(session.AccessToken, "1234567890")
+}
+
+// newTestProvider spins up a fake identity provider serving a discovery document
+// and an empty JWKS endpoint, and returns a provider configured against it. It
+// serves no userinfo endpoint.
+func newTestProvider(t *testing.T) (*openidconnect.Provider, *httptest.Server) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ts.URL,
+			"authorization_endpoint": ts.URL + "/auth",
+			"token_endpoint":         ts.URL + "/token",
+			"userinfo_endpoint":      ts.URL + "/userinfo",
+			"jwks_uri":               ts.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[]}`))
+	})
+
+	provider, err := openidconnect.New("key", "secret", "/foo", ts.URL+"/.well-known/openid-configuration")
+	if err != nil {
+		t.Fatalf("unexpected error creating provider: %v", err)
+	}
+
+	return provider, ts
+}
+
+func Test_FetchUser(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.NoError(err)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	idToken := signRS256(t, key, "test-kid", map[string]interface{}{
+		"iss": ts.URL,
+		"aud": "key",
+		"sub": "abc123",
+		"exp": float64(9999999999),
+	})
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ts.URL,
+			"authorization_endpoint": ts.URL + "/auth",
+			"token_endpoint":         ts.URL + "/token",
+			"userinfo_endpoint":      ts.URL + "/userinfo",
+			"jwks_uri":               ts.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-kid",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		a.Equal(r.Header.Get("Authorization"), "Bearer access-token")
+		a.Empty(r.URL.Query().Get("access_token"))
+		json.NewEncoder(w).Encode(map[string]string{
+			"email":              "jane@example.com",
+			"name":               "Jane Doe",
+			"preferred_username": "jane",
+		})
+	})
+
+	provider, err := openidconnect.New("key", "secret", "/foo", ts.URL+"/.well-known/openid-configuration")
+	a.NoError(err)
+
+	user, err := provider.FetchUser(&openidconnect.Session{
+		AccessToken: "access-token",
+		IDToken:     idToken,
+	})
+	a.NoError(err)
+	a.Equal(user.UserID, "abc123")
+	a.Equal(user.Email, "jane@example.com")
+	a.Equal(user.Name, "Jane Doe")
+	a.Equal(user.NickName, "jane")
+}
+
+func Test_FetchUser_RejectsMissingExpClaim(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.NoError(err)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	idToken := signRS256(t, key, "test-kid", map[string]interface{}{
+		"iss": ts.URL,
+		"aud": "key",
+		"sub": "abc123",
+	})
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ts.URL,
+			"authorization_endpoint": ts.URL + "/auth",
+			"token_endpoint":         ts.URL + "/token",
+			"userinfo_endpoint":      ts.URL + "/userinfo",
+			"jwks_uri":               ts.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-kid",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+
+	provider, err := openidconnect.New("key", "secret", "/foo", ts.URL+"/.well-known/openid-configuration")
+	a.NoError(err)
+
+	_, err = provider.FetchUser(&openidconnect.Session{
+		AccessToken: "access-token",
+		IDToken:     idToken,
+	})
+	a.Error(err)
+	a.Contains(err.Error(), "exp")
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+
+	signingInput := fmt.Sprintf("%s.%s",
+		base64.RawURLEncoding.EncodeToString(headerJSON),
+		base64.RawURLEncoding.EncodeToString(claimsJSON))
+
+	hashed := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("unexpected error signing id_token: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
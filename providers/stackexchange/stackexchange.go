@@ -4,12 +4,12 @@ package stackexchange
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"crypto/hmac"
 	"crypto/sha256"
@@ -20,9 +20,12 @@ import (
 )
 
 const (
-	authURL         string = "https://stackexchange.com/oauth"
-	tokenURL        string = "https://stackexchange.com/oauth/access_token/json"
-	endpointProfile string = "https://api.stackexchange.com/me?site=stackoverflow"
+	authURL            string = "https://stackexchange.com/oauth"
+	tokenURL           string = "https://stackexchange.com/oauth/access_token/json"
+	endpointProfile    string = "https://api.stackexchange.com/me?site="
+	endpointAssociated string = "https://api.stackexchange.com/me/associated?"
+
+	defaultSite string = "stackoverflow"
 )
 
 // New creates a new StackExchange provider, and sets up important connection details.
@@ -30,25 +33,35 @@ const (
 // one manually.
 func New(clientKey, secret, clientAccessKey, callbackURL string, scopes ...string) *Provider {
 	p := &Provider{
-		ClientKey:    clientKey,
-		Secret:       secret,
-		ClientAccessKey:    clientAccessKey,
-		CallbackURL:  callbackURL,
-		providerName: "stackexchange",
+		ClientKey:       clientKey,
+		Secret:          secret,
+		ClientAccessKey: clientAccessKey,
+		CallbackURL:     callbackURL,
+		Site:            defaultSite,
+		providerName:    "stackexchange",
 	}
 	p.config = newConfig(p, scopes)
 	return p
 }
 
+// WithSite sets the Stack Exchange network site (e.g. "superuser", "askubuntu")
+// the provider should fetch the authenticated user's profile from, and returns
+// the provider so it can be chained off of `New`. It defaults to "stackoverflow".
+func (p *Provider) WithSite(site string) *Provider {
+	p.Site = site
+	return p
+}
+
 // Provider is the implementation of `goth.Provider` for accessing Facebook.
 type Provider struct {
-	ClientKey    string
-	Secret       string
-	ClientAccessKey	string
-	CallbackURL  string
-	HTTPClient   *http.Client
-	config       *oauth2.Config
-	providerName string
+	ClientKey       string
+	Secret          string
+	ClientAccessKey string
+	CallbackURL     string
+	Site            string
+	HTTPClient      *http.Client
+	config          *oauth2.Config
+	providerName    string
 }
 
 // Name is the name used to retrieve this provider later.
@@ -98,7 +111,12 @@ func (p *Provider) FetchUser(session goth.Session) (goth.User, error) {
 	hash.Write([]byte(sess.AccessToken))
 	appsecretProof := hex.EncodeToString(hash.Sum(nil))
 
-	response, err := p.Client().Get(endpointProfile + "&access_token=" + url.QueryEscape(sess.AccessToken) + "&key=" + p.ClientAccessKey + "&appsecret_proof=" + appsecretProof)
+	site := p.Site
+	if site == "" {
+		site = defaultSite
+	}
+
+	response, err := p.Client().Get(endpointProfile + url.QueryEscape(site) + "&access_token=" + url.QueryEscape(sess.AccessToken) + "&key=" + p.ClientAccessKey + "&appsecret_proof=" + appsecretProof)
 	if err != nil {
 		return user, err
 	}
@@ -118,8 +136,66 @@ func (p *Provider) FetchUser(session goth.Session) (goth.User, error) {
 		return user, err
 	}
 
-	err = userFromReader(bytes.NewReader(bits), &user)
-	return user, err
+	if err := userFromReader(bytes.NewReader(bits), &user); err != nil {
+		return user, err
+	}
+
+	// NetworkAccounts is attached on a best-effort basis: a rate-limited or
+	// otherwise failing /me/associated call shouldn't break an otherwise
+	// successful login.
+	if accounts, err := p.FetchSites(session); err == nil {
+		if rawData, ok := user.RawData.(map[string]interface{}); ok {
+			rawData["NetworkAccounts"] = accounts
+			user.RawData = rawData
+		}
+	}
+
+	return user, nil
+}
+
+// NetworkAccount represents a single Stack Exchange network site the
+// authenticated user is registered on, as returned by `/me/associated`.
+type NetworkAccount struct {
+	SiteName   string `json:"site_name"`
+	SiteURL    string `json:"site_url"`
+	UserID     int    `json:"user_id"`
+	AccountID  int    `json:"account_id"`
+	Reputation int    `json:"reputation"`
+}
+
+// FetchSites goes to Stack Exchange and returns every network account
+// associated with the user's authenticated session, across all Stack
+// Exchange sites, via the `/me/associated` endpoint.
+func (p *Provider) FetchSites(session goth.Session) ([]NetworkAccount, error) {
+	sess := session.(*Session)
+
+	if sess.AccessToken == "" {
+		return nil, fmt.Errorf("%s cannot get associated accounts without accessToken", p.providerName)
+	}
+
+	response, err := p.Client().Get(endpointAssociated + "access_token=" + url.QueryEscape(sess.AccessToken) + "&key=" + p.ClientAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with a %d trying to fetch associated accounts", p.providerName, response.StatusCode)
+	}
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	a := struct {
+		Items []NetworkAccount `json:"items"`
+	}{}
+	if err := json.NewDecoder(bytes.NewReader(bits)).Decode(&a); err != nil {
+		return nil, err
+	}
+
+	return a.Items, nil
 }
 
 func userFromReader(reader io.Reader, user *goth.User) error {
@@ -182,12 +258,55 @@ func newConfig(provider *Provider, scopes []string) *oauth2.Config {
 	return c
 }
 
-//RefreshToken refresh token is not provided by facebook
+// RefreshToken refreshes the access token using Stack Exchange's dedicated
+// refresh endpoint. Note that Stack Exchange does not issue a refresh_token
+// at all if the `no_expiry` scope was requested when authorizing, since
+// those access tokens never expire and so have nothing to refresh.
 func (p *Provider) RefreshToken(refreshToken string) (*oauth2.Token, error) {
-	return nil, errors.New("Refresh token is not provided by stackexchange")
+	values := url.Values{
+		"client_id":     {p.ClientKey},
+		"client_secret": {p.Secret},
+		"refresh_token": {refreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+
+	response, err := p.Client().PostForm(tokenURL, values)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with a %d trying to refresh the access token", p.providerName, response.StatusCode)
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		Expires     int    `json:"expires"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		RefreshToken: refreshToken,
+	}
+	if t.Expires > 0 {
+		token.Expiry = time.Now().Add(time.Duration(t.Expires) * time.Second)
+	}
+
+	return token, nil
 }
 
-//RefreshTokenAvailable refresh token is not provided by facebook
+// RefreshTokenAvailable refresh token is provided by stackexchange unless the
+// `no_expiry` scope was requested, in which case the access token never
+// expires and Stack Exchange does not issue a refresh_token for it.
 func (p *Provider) RefreshTokenAvailable() bool {
-	return false
+	for _, scope := range p.config.Scopes {
+		if scope == "no_expiry" {
+			return false
+		}
+	}
+	return true
 }
@@ -2,6 +2,9 @@ package stackexchange_test
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 
@@ -18,6 +21,15 @@ func Test_New(t *testing.T) {
 	a.Equal(provider.ClientKey, os.Getenv("STACKEXCHANGE_KEY"))
 	a.Equal(provider.Secret, os.Getenv("STACKEXCHANGE_SECRET"))
 	a.Equal(provider.CallbackURL, "/foo")
+	a.Equal(provider.Site, "stackoverflow")
+}
+
+func Test_WithSite(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider := stackexchangeProvider().WithSite("superuser")
+	a.Equal(provider.Site, "superuser")
 }
 
 func Test_Implements_Provider(t *testing.T) {
@@ -54,6 +66,140 @@ func Test_SessionFromJSON(t *testing.T) {
 	a.Equal(session.AccessToken, "1234567890")
 }
 
+func Test_FetchSites(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Contains(r.URL.RawQuery, "access_token=1234567890")
+		w.Write([]byte(`{"items":[{"site_name":"Super User","site_url":"https://superuser.com","user_id":1,"account_id":1,"reputation":42}]}`))
+	}))
+	defer ts.Close()
+
+	provider := stackexchangeProvider()
+	provider.HTTPClient = redirectingClient(ts.URL)
+
+	accounts, err := provider.FetchSites(&stackexchange.Session{AccessToken: "1234567890"})
+	a.NoError(err)
+	a.Len(accounts, 1)
+	a.Equal(accounts[0].SiteName, "Super User")
+	a.Equal(accounts[0].Reputation, 42)
+}
+
+func Test_FetchUser_UsesSiteAndAttachesNetworkAccounts(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		a.Contains(r.URL.RawQuery, "site=superuser")
+		w.Write([]byte(`{"items":[{"user_id":1,"display_name":"Jane Doe"}]}`))
+	})
+	mux.HandleFunc("/me/associated", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[{"site_name":"Super User","site_url":"https://superuser.com","user_id":1,"account_id":1,"reputation":42}]}`))
+	})
+
+	provider := stackexchangeProvider().WithSite("superuser")
+	provider.HTTPClient = redirectingClient(ts.URL)
+
+	user, err := provider.FetchUser(&stackexchange.Session{AccessToken: "1234567890"})
+	a.NoError(err)
+
+	rawData, ok := user.RawData.(map[string]interface{})
+	a.True(ok)
+
+	accounts, ok := rawData["NetworkAccounts"].([]stackexchange.NetworkAccount)
+	a.True(ok)
+	a.Len(accounts, 1)
+	a.Equal(accounts[0].SiteName, "Super User")
+}
+
+func Test_FetchUser_SucceedsWhenFetchSitesFails(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[{"user_id":1,"display_name":"Jane Doe"}]}`))
+	})
+	mux.HandleFunc("/me/associated", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	provider := stackexchangeProvider()
+	provider.HTTPClient = redirectingClient(ts.URL)
+
+	user, err := provider.FetchUser(&stackexchange.Session{AccessToken: "1234567890"})
+	a.NoError(err)
+	a.Equal(user.NickName, "Jane Doe")
+
+	rawData, ok := user.RawData.(map[string]interface{})
+	a.True(ok)
+	a.NotContains(rawData, "NetworkAccounts")
+}
+
+func Test_RefreshTokenAvailable(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	a.True(stackexchangeProvider().RefreshTokenAvailable())
+}
+
+func Test_RefreshTokenAvailable_FalseWithNoExpiryScope(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	provider := stackexchange.New(os.Getenv("STACKEXCHANGE_KEY"), os.Getenv("STACKEXCHANGE_SECRET"), "", "/foo", "private_info", "no_expiry")
+	a.False(provider.RefreshTokenAvailable())
+}
+
+func Test_RefreshToken(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.NoError(r.ParseForm())
+		a.Equal(r.FormValue("grant_type"), "refresh_token")
+		a.Equal(r.FormValue("refresh_token"), "old_refresh_token")
+		w.Write([]byte(`{"access_token":"new_access_token","expires":86399}`))
+	}))
+	defer ts.Close()
+
+	provider := stackexchangeProvider()
+	provider.HTTPClient = redirectingClient(ts.URL)
+
+	token, err := provider.RefreshToken("old_refresh_token")
+	a.NoError(err)
+	a.Equal(token.AccessToken, "new_access_token")
+	a.False(token.Expiry.IsZero())
+}
+
+// redirectingClient returns an *http.Client that sends every request to target,
+// so the stackexchange package's hardcoded API URLs can be exercised against a
+// local httptest.Server.
+func redirectingClient(target string) *http.Client {
+	targetURL, _ := url.Parse(target)
+	return &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 func stackexchangeProvider() *stackexchange.Provider {
 	return stackexchange.New(os.Getenv("STACKEXCHANGE_KEY"), os.Getenv("STACKEXCHANGE_SECRET"), "/foo", "private_info")
 }
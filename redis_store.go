@@ -0,0 +1,75 @@
+package goth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisClient is the subset of github.com/go-redis/redis/v8's *redis.Client
+// that RedisStore needs. A genuine *redis.Client satisfies this interface
+// as-is, so it can be passed straight through without an adapter.
+type RedisClient interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+}
+
+// RedisStore persists sessions server-side in Redis, keeping only a random
+// session id in the browser cookie. This is the store to reach for once a
+// session carries a long-lived refresh token or id_token that shouldn't sit
+// in the browser at all.
+type RedisStore struct {
+	Client RedisClient
+	// TTL controls how long Redis retains the session. Zero means no expiry.
+	TTL time.Duration
+	// KeyPrefix namespaces the Redis keys RedisStore writes. Defaults to "goth_session:".
+	KeyPrefix string
+
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+}
+
+func (s *RedisStore) prefix() string {
+	if s.KeyPrefix != "" {
+		return s.KeyPrefix
+	}
+	return "goth_session:"
+}
+
+// Save implements SessionStore.
+func (s *RedisStore) Save(w http.ResponseWriter, name, value string) error {
+	id, err := randomSessionID()
+	if err != nil {
+		return err
+	}
+
+	if err := s.Client.Set(context.Background(), s.prefix()+name+":"+id, value, s.TTL).Err(); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    id,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		Secure:   s.Secure,
+		HttpOnly: s.HttpOnly,
+	})
+
+	return nil
+}
+
+// Load implements SessionStore.
+func (s *RedisStore) Load(r *http.Request, name string) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", fmt.Errorf("goth: no session found for %q", name)
+	}
+
+	return s.Client.Get(context.Background(), s.prefix()+name+":"+cookie.Value).Result()
+}
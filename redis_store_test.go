@@ -0,0 +1,67 @@
+package goth_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/netcessities/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedisClient implements goth.RedisClient directly against an in-memory
+// map, matching the real *redis.Client method signatures so it exercises the
+// same .Err()/.Result() call sites RedisStore uses.
+type fakeRedisClient struct {
+	data map[string]string
+}
+
+func newFakeRedisClient() *fakeRedisClient {
+	return &fakeRedisClient{data: map[string]string{}}
+}
+
+func (c *fakeRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	c.data[key] = value.(string)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *fakeRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	v, ok := c.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func Test_RedisStore_RoundTrip(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.RedisStore{Client: newFakeRedisClient()}
+
+	res := httptest.NewRecorder()
+	a.NoError(store.Save(res, "sess", "a redis-backed session"))
+
+	// the browser only ever sees a random session id, never the payload
+	cookies := res.Result().Cookies()
+	a.Len(cookies, 1)
+	a.NotEqual(cookies[0].Value, "a redis-backed session")
+
+	req := requestWithCookies(res)
+	loaded, err := store.Load(req, "sess")
+	a.NoError(err)
+	a.Equal(loaded, "a redis-backed session")
+}
+
+func Test_RedisStore_Load_MissingSession(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.RedisStore{Client: newFakeRedisClient()}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	_, err := store.Load(req, "sess")
+	a.Error(err)
+}
@@ -0,0 +1,237 @@
+package goth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// SessionStore persists the marshalled form of a Session (see Session.Marshal)
+// across the redirect to the provider and back. gothic uses a SessionStore
+// instead of talking to gorilla/sessions directly so the browser-cookie
+// transport can be swapped out, which matters once a session carries an
+// oversized token such as an OpenID Connect id_token.
+type SessionStore interface {
+	// Save persists value, the output of Session.Marshal, under name.
+	Save(w http.ResponseWriter, name, value string) error
+	// Load retrieves the value previously stored under name.
+	Load(r *http.Request, name string) (string, error)
+}
+
+// defaultCookieSizeThreshold keeps a single cookie under the common 4 KB
+// per-cookie browser limit, leaving room for the cookie's own name and attributes.
+const defaultCookieSizeThreshold = 3800
+
+// CookieStore is the default SessionStore, and preserves goth's historical
+// behavior of keeping everything in the browser. It transparently splits the
+// marshalled session across name_0, name_1, ... cookies once the payload
+// would otherwise exceed MaxCookieSize, reassembles them on Load, and
+// protects the payload's integrity with either EncryptionKey or SigningKey -
+// one of the two must be set, since an auth library handing a client a cookie
+// it can read and tamper with unnoticed (the provider session carries the
+// access/refresh/id tokens) is not an acceptable default.
+type CookieStore struct {
+	// MaxCookieSize is the largest payload, in bytes, allowed in a single
+	// cookie before CookieStore starts splitting. Defaults to ~3800 bytes.
+	MaxCookieSize int
+
+	// EncryptionKey, when set, must be 16, 24, or 32 bytes to select
+	// AES-128/192/256-GCM. The payload is encrypted (and thereby
+	// authenticated) before it is split and decrypted after it is
+	// reassembled. Takes precedence over SigningKey if both are set.
+	EncryptionKey []byte
+
+	// SigningKey, when set and EncryptionKey is not, HMAC-SHA256 signs the
+	// payload so Load can detect tampering. The payload itself remains
+	// readable in the cookie; set EncryptionKey instead if that isn't
+	// acceptable.
+	SigningKey []byte
+
+	// Path, Domain, Secure, HttpOnly, and MaxAge are applied to every cookie
+	// written by Save, mirroring the fields on http.Cookie.
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+	MaxAge   int
+}
+
+func (c *CookieStore) threshold() int {
+	if c.MaxCookieSize > 0 {
+		return c.MaxCookieSize
+	}
+	return defaultCookieSizeThreshold
+}
+
+// Save implements SessionStore.
+func (c *CookieStore) Save(w http.ResponseWriter, name, value string) error {
+	if len(c.EncryptionKey) == 0 && len(c.SigningKey) == 0 {
+		return fmt.Errorf("goth: CookieStore requires EncryptionKey or SigningKey to be set")
+	}
+
+	payload := []byte(value)
+
+	if len(c.EncryptionKey) > 0 {
+		encrypted, err := encrypt(c.EncryptionKey, payload)
+		if err != nil {
+			return err
+		}
+		payload = encrypted
+	} else {
+		payload = append(sign(c.SigningKey, payload), payload...)
+	}
+
+	encoded := base64.URLEncoding.EncodeToString(payload)
+	chunks := chunkString(encoded, c.threshold())
+
+	for i, part := range chunks {
+		c.setCookie(w, chunkCookieName(name, i), part)
+	}
+	c.setCookie(w, chunkCountCookieName(name), strconv.Itoa(len(chunks)))
+
+	return nil
+}
+
+// Load implements SessionStore.
+func (c *CookieStore) Load(r *http.Request, name string) (string, error) {
+	if len(c.EncryptionKey) == 0 && len(c.SigningKey) == 0 {
+		return "", fmt.Errorf("goth: CookieStore requires EncryptionKey or SigningKey to be set")
+	}
+
+	countCookie, err := r.Cookie(chunkCountCookieName(name))
+	if err != nil {
+		return "", fmt.Errorf("goth: no session found for %q", name)
+	}
+
+	count, err := strconv.Atoi(countCookie.Value)
+	if err != nil || count <= 0 {
+		return "", fmt.Errorf("goth: malformed session chunk count for %q", name)
+	}
+
+	var encoded string
+	for i := 0; i < count; i++ {
+		cookie, err := r.Cookie(chunkCookieName(name, i))
+		if err != nil {
+			return "", fmt.Errorf("goth: missing session chunk %d for %q", i, name)
+		}
+		encoded += cookie.Value
+	}
+
+	payload, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.EncryptionKey) > 0 {
+		decrypted, err := decrypt(c.EncryptionKey, payload)
+		if err != nil {
+			return "", err
+		}
+		payload = decrypted
+	} else {
+		macSize := sha256.Size
+		if len(payload) < macSize {
+			return "", fmt.Errorf("goth: session payload for %q is shorter than its signature", name)
+		}
+
+		mac, signed := payload[:macSize], payload[macSize:]
+		if !hmac.Equal(mac, sign(c.SigningKey, signed)) {
+			return "", fmt.Errorf("goth: session payload for %q failed signature verification", name)
+		}
+		payload = signed
+	}
+
+	return string(payload), nil
+}
+
+func (c *CookieStore) setCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		MaxAge:   c.MaxAge,
+	})
+}
+
+func chunkCookieName(name string, i int) string {
+	return name + "_" + strconv.Itoa(i)
+}
+
+func chunkCountCookieName(name string) string {
+	return name + "_n"
+}
+
+func chunkString(s string, size int) []string {
+	if size <= 0 || len(s) <= size {
+		return []string{s}
+	}
+
+	chunks := make([]string, 0, len(s)/size+1)
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
+
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("goth: ciphertext is shorter than the GCM nonce")
+	}
+
+	nonce, rest := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, rest, nil)
+}
+
+func sign(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,116 @@
+package goth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/netcessities/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CookieStore_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	store := &goth.CookieStore{SigningKey: []byte("signing-key")}
+	roundTrip(t, store, "small payload")
+}
+
+func Test_CookieStore_SigningDetectsTampering(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.CookieStore{SigningKey: []byte("signing-key")}
+	res := httptest.NewRecorder()
+	a.NoError(store.Save(res, "sess", "payload"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range res.Result().Cookies() {
+		if c.Name == "sess_0" {
+			c.Value += "tampered"
+		}
+		req.AddCookie(c)
+	}
+
+	_, err := store.Load(req, "sess")
+	a.Error(err)
+}
+
+func Test_CookieStore_Save_RequiresAKey(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.CookieStore{}
+	a.Error(store.Save(httptest.NewRecorder(), "sess", "payload"))
+}
+
+func Test_CookieStore_SplitsOversizedPayloads(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.CookieStore{MaxCookieSize: 16, SigningKey: []byte("signing-key")}
+	res := httptest.NewRecorder()
+
+	value := strings.Repeat("x", 100)
+	a.NoError(store.Save(res, "sess", value))
+
+	cookieCount := len(res.Result().Cookies())
+	a.Greater(cookieCount, 2, "expected the payload to be split across multiple cookies")
+
+	req := requestWithCookies(res)
+	loaded, err := store.Load(req, "sess")
+	a.NoError(err)
+	a.Equal(loaded, value)
+}
+
+func Test_CookieStore_Encryption(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.CookieStore{EncryptionKey: []byte("0123456789abcdef")}
+	res := httptest.NewRecorder()
+
+	a.NoError(store.Save(res, "sess", "top secret"))
+
+	for _, c := range res.Result().Cookies() {
+		a.NotContains(c.Value, "top secret")
+	}
+
+	req := requestWithCookies(res)
+	loaded, err := store.Load(req, "sess")
+	a.NoError(err)
+	a.Equal(loaded, "top secret")
+}
+
+func Test_CookieStore_Load_MissingSession(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+
+	store := &goth.CookieStore{SigningKey: []byte("signing-key")}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, err := store.Load(req, "sess")
+	a.Error(err)
+}
+
+func roundTrip(t *testing.T, store goth.SessionStore, value string) {
+	t.Helper()
+	a := assert.New(t)
+
+	res := httptest.NewRecorder()
+	a.NoError(store.Save(res, "sess", value))
+
+	req := requestWithCookies(res)
+	loaded, err := store.Load(req, "sess")
+	a.NoError(err)
+	a.Equal(loaded, value)
+}
+
+func requestWithCookies(res *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range res.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	return req
+}